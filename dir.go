@@ -0,0 +1,157 @@
+// Copyright 2009 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fileserver
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// SafeDir implements [http.FileSystem] using the native file system
+// restricted to a specific directory, like [http.Dir], but with the
+// hardening [http.Dir] lacks: path components are validated before they
+// ever reach the OS, and the resolved file is checked against symlink
+// escapes out of Root.
+//
+// An empty SafeDir is treated as ".".
+type SafeDir struct {
+	// Root is the directory to serve files from.
+	Root string
+
+	// ForbidDotfiles rejects any request whose path contains a component
+	// beginning with a dot, e.g. ".git" or ".env".
+	ForbidDotfiles bool
+}
+
+// Open implements [http.FileSystem].
+func (d SafeDir) Open(name string) (http.File, error) {
+	if filepath.Separator != '/' && strings.ContainsRune(name, filepath.Separator) {
+		return nil, errors.New("http: invalid character in file path")
+	}
+
+	root := d.Root
+	if root == "" {
+		root = "."
+	}
+
+	if !safeFilePath(name) {
+		return nil, fs.ErrNotExist
+	}
+
+	if d.ForbidDotfiles && hasDotfileComponent(name) {
+		return nil, fs.ErrNotExist
+	}
+
+	fullName := filepath.Join(root, filepath.FromSlash(path.Clean("/"+name)))
+
+	f, err := os.Open(fullName)
+	if err != nil {
+		return nil, mapOpenError(err, fullName)
+	}
+
+	rootReal, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		f.Close()
+		return nil, mapOpenError(err, fullName)
+	}
+
+	fileReal, err := filepath.EvalSymlinks(fullName)
+	if err != nil {
+		f.Close()
+		return nil, mapOpenError(err, fullName)
+	}
+
+	if fileReal != rootReal && !strings.HasPrefix(fileReal, rootReal+string(filepath.Separator)) {
+		f.Close()
+		return nil, fs.ErrNotExist
+	}
+
+	return f, nil
+}
+
+// hasDotfileComponent reports whether any slash-separated component of
+// name begins with a dot.
+func hasDotfileComponent(name string) bool {
+	for _, part := range strings.Split(name, "/") {
+		if strings.HasPrefix(part, ".") && part != "." && part != ".." {
+			return true
+		}
+	}
+
+	return false
+}
+
+// safeFilePath reports whether name is safe to resolve against a root
+// directory: free of NUL bytes and of path components that resolve to
+// reserved device names on Windows.
+func safeFilePath(name string) bool {
+	if strings.IndexByte(name, 0) >= 0 {
+		return false
+	}
+
+	for _, part := range strings.Split(name, "/") {
+		if part == "" || part == "." || part == ".." {
+			continue
+		}
+
+		if isReservedName(part) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// reservedNames lists the Windows device names that are unsafe to use as
+// a path component regardless of extension.
+var reservedNames = map[string]struct{}{
+	"CON": {}, "PRN": {}, "AUX": {}, "NUL": {},
+	"COM1": {}, "COM2": {}, "COM3": {}, "COM4": {}, "COM5": {},
+	"COM6": {}, "COM7": {}, "COM8": {}, "COM9": {},
+	"LPT1": {}, "LPT2": {}, "LPT3": {}, "LPT4": {}, "LPT5": {},
+	"LPT6": {}, "LPT7": {}, "LPT8": {}, "LPT9": {},
+}
+
+func isReservedName(part string) bool {
+	base := part
+	if i := strings.IndexByte(base, '.'); i >= 0 {
+		base = base[:i]
+	}
+
+	_, ok := reservedNames[strings.ToUpper(base)]
+	return ok
+}
+
+// mapOpenError maps OS-specific errors returned from opening fullName,
+// such as ENOTDIR when a parent component is actually a regular file,
+// into fs.ErrNotExist so toHTTPError reports 404 instead of 500.
+func mapOpenError(originalErr error, fullName string) error {
+	if os.IsNotExist(originalErr) || os.IsPermission(originalErr) {
+		return originalErr
+	}
+
+	parts := strings.Split(fullName, string(filepath.Separator))
+	for i := range parts {
+		if parts[i] == "" {
+			continue
+		}
+
+		fi, err := os.Stat(strings.Join(parts[:i+1], string(filepath.Separator)))
+		if err != nil {
+			return originalErr
+		}
+
+		if !fi.IsDir() {
+			return fs.ErrNotExist
+		}
+	}
+
+	return originalErr
+}
@@ -0,0 +1,140 @@
+package fileserver
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestSafeDirOpen(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "sub", "file.txt"), []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	sd := SafeDir{Root: dir}
+
+	f, err := sd.Open("/sub/file.txt")
+	if err != nil {
+		t.Fatalf("Open() = %v, want nil error", err)
+	}
+
+	defer f.Close()
+}
+
+func TestSafeDirSymlinkEscape(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks require elevated privileges on windows")
+	}
+
+	base := t.TempDir()
+	root := filepath.Join(base, "root")
+	secret := filepath.Join(base, "secret")
+
+	if err := os.Mkdir(root, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Mkdir(secret, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(secret, "secret.txt"), []byte("top secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Symlink(secret, filepath.Join(root, "escape")); err != nil {
+		t.Fatal(err)
+	}
+
+	sd := SafeDir{Root: root}
+
+	_, err := sd.Open("/escape/secret.txt")
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("Open() = %v, want fs.ErrNotExist for a symlink escaping Root", err)
+	}
+}
+
+func TestSafeDirDotDotTraversal(t *testing.T) {
+	base := t.TempDir()
+	root := filepath.Join(base, "root")
+	if err := os.Mkdir(root, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(base, "outside.txt"), []byte("nope"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	sd := SafeDir{Root: root}
+
+	_, err := sd.Open("/../outside.txt")
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("Open() = %v, want fs.ErrNotExist for a path escaping Root via ..", err)
+	}
+}
+
+func TestSafeDirRejectsNulByte(t *testing.T) {
+	sd := SafeDir{Root: t.TempDir()}
+
+	_, err := sd.Open("/foo\x00bar")
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("Open() = %v, want fs.ErrNotExist for a path containing a NUL byte", err)
+	}
+}
+
+func TestSafeDirRejectsReservedNames(t *testing.T) {
+	sd := SafeDir{Root: t.TempDir()}
+
+	for _, name := range []string{"/con", "/CON.txt", "/nul", "/com1.log"} {
+		if _, err := sd.Open(name); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("Open(%q) = %v, want fs.ErrNotExist for a reserved device name", name, err)
+		}
+	}
+}
+
+func TestSafeDirForbidDotfiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ".secret"), []byte("shh"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	forbidding := SafeDir{Root: dir, ForbidDotfiles: true}
+	if _, err := forbidding.Open("/.secret"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("Open() = %v, want fs.ErrNotExist when ForbidDotfiles is set", err)
+	}
+
+	allowing := SafeDir{Root: dir}
+	f, err := allowing.Open("/.secret")
+	if err != nil {
+		t.Fatalf("Open() = %v, want nil error when ForbidDotfiles is unset", err)
+	}
+
+	f.Close()
+}
+
+func TestSafeDirMapsNotADirectoryTo404(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "file.txt"), []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	sd := SafeDir{Root: dir}
+
+	_, err := sd.Open("/file.txt/sub")
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("Open() = %v, want fs.ErrNotExist when a path component is a regular file", err)
+	}
+
+	if got, want := toHTTPError(err), http.StatusNotFound; got != want {
+		t.Errorf("toHTTPError() = %d, want %d", got, want)
+	}
+}
@@ -0,0 +1,44 @@
+package fileserver
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+)
+
+// defaultETagMaxSize is the largest file DefaultETagFunc will hash to
+// produce a strong Etag. Files larger than this get a cheap weak Etag
+// derived from their size and modification time instead.
+const defaultETagMaxSize = 32 << 20 // 32 MiB
+
+// ETagFunc computes the Etag for a file served by a handler returned by
+// [FileServer] with [WithETagFunc]. weak reports whether the returned etag
+// should be written as a weak validator (W/"..."); etag itself must not
+// include the surrounding quotes, as the caller adds them.
+type ETagFunc func(name string, fi fs.FileInfo, f http.File) (etag string, weak bool, err error)
+
+// DefaultETagFunc is an [ETagFunc] suitable as a sane default: it hashes
+// the file contents with SHA-256 to produce a strong Etag, rewinding f
+// afterwards so the caller can still read it from the start. Files
+// larger than 32 MiB are given a weak Etag derived from their size and
+// modification time instead, to avoid hashing large files on every
+// request.
+func DefaultETagFunc(name string, fi fs.FileInfo, f http.File) (string, bool, error) {
+	if fi.Size() > defaultETagMaxSize {
+		return fmt.Sprintf("%x-%x", fi.Size(), fi.ModTime().UnixNano()), true, nil
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", false, err
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", false, err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), false, nil
+}
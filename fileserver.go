@@ -56,12 +56,19 @@ func FileServer(
 	root http.FileSystem,
 	errHandler func(http.ResponseWriter, *http.Request, int, error),
 	dirListHandler func(http.ResponseWriter, *http.Request, []FileEntry),
+	opts ...Option,
 ) *fileHandler {
-	return &fileHandler{
+	h := &fileHandler{
 		root:           root,
 		errHandler:     errHandler,
 		dirListHandler: dirListHandler,
 	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
 }
 
 // FileEntry contains metadata for a file found in a filesystem.
@@ -70,10 +77,35 @@ type FileEntry struct {
 	Name string
 }
 
+// Option configures optional behavior on a handler returned by
+// [FileServer]. [FileServerFS] takes no options.
+type Option func(*fileHandler)
+
+// WithETagFunc sets the function used to compute the Etag header for
+// served files. See [ETagFunc] for details.
+func WithETagFunc(fn ETagFunc) Option {
+	return func(h *fileHandler) {
+		h.etagFunc = fn
+	}
+}
+
+// WithPrecompressedEncodings opts a handler into serving pre-compressed
+// sidecar files (name+".br", name+".zst", name+".gz") instead of name
+// itself when the client's Accept-Encoding allows it. encodings
+// restricts negotiation to the given content codings; supported values
+// are "br", "zstd", and "gzip".
+func WithPrecompressedEncodings(encodings ...string) Option {
+	return func(h *fileHandler) {
+		h.precompressedEncodings = encodings
+	}
+}
+
 type fileHandler struct {
-	root           http.FileSystem
-	errHandler     func(http.ResponseWriter, *http.Request, int, error)
-	dirListHandler func(http.ResponseWriter, *http.Request, []FileEntry)
+	root                   http.FileSystem
+	errHandler             func(http.ResponseWriter, *http.Request, int, error)
+	dirListHandler         func(http.ResponseWriter, *http.Request, []FileEntry)
+	etagFunc               ETagFunc
+	precompressedEncodings []string
 }
 
 func (h *fileHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -102,6 +134,8 @@ func (h *fileHandler) serveFile(w http.ResponseWriter, r *http.Request, fs http.
 
 	defer f.Close()
 
+	servedPath := name
+
 	d, err := f.Stat()
 	if err != nil {
 		code := toHTTPError(err)
@@ -139,6 +173,7 @@ func (h *fileHandler) serveFile(w http.ResponseWriter, r *http.Request, fs http.
 			if err == nil {
 				d = dd
 				f = ff
+				servedPath = index
 			}
 		}
 	}
@@ -153,8 +188,37 @@ func (h *fileHandler) serveFile(w http.ResponseWriter, r *http.Request, fs http.
 		return
 	}
 
+	contentName := d.Name()
+
+	if len(h.precompressedEncodings) > 0 {
+		if sd, sf, coding, ok := h.openPrecompressed(fs, servedPath, r); ok {
+			defer sf.Close()
+			d = sd
+			f = sf
+			w.Header().Set("Content-Encoding", coding)
+			w.Header().Add("Vary", "Accept-Encoding")
+		}
+	}
+
+	if h.etagFunc != nil {
+		etag, weak, err := h.etagFunc(contentName, d, f)
+		if err != nil {
+			h.errHandler(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		if etag != "" {
+			if weak {
+				etag = `W/"` + etag + `"`
+			} else {
+				etag = `"` + etag + `"`
+			}
+			w.Header().Set("Etag", etag)
+		}
+	}
+
 	sizeFunc := func() (int64, error) { return d.Size(), nil }
-	h.serveContent(w, r, d.Name(), d.ModTime(), sizeFunc, f)
+	h.serveContent(w, r, contentName, d.ModTime(), sizeFunc, f)
 }
 
 type anyDirs interface {
@@ -322,6 +386,7 @@ func (h *fileHandler) serveContent(w http.ResponseWriter, r *http.Request, name
 
 	w.Header().Set("Accept-Ranges", "bytes")
 
+	w.Header().Del("Content-Length")
 	if len(ranges) > 0 || w.Header().Get("Content-Encoding") == "" {
 		w.Header().Set("Content-Length", strconv.FormatInt(sendSize, 10))
 	}
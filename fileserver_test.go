@@ -1,20 +1,25 @@
 package fileserver
 
 import (
-	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
+	"io/fs"
 	"net/http"
 	"net/http/httptest"
 	"os"
-	"os/signal"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestFileServer(t *testing.T) {
 	fs := FileServer(
 		http.Dir("./testdata"),
-		func(w http.ResponseWriter, r *http.Request, i int) {
-			http.Error(w, http.StatusText(i), i)
+		func(w http.ResponseWriter, r *http.Request, code int, err error) {
+			http.Error(w, http.StatusText(code), code)
 		},
 		func(w http.ResponseWriter, r *http.Request, fe []FileEntry) {
 			w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -32,13 +37,312 @@ func TestFileServer(t *testing.T) {
 	)
 
 	srv := httptest.NewServer(fs)
+	defer srv.Close()
 
-	t.Logf("Started server on %s", srv.URL)
+	res, err := http.Get(srv.URL + "/hello.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
 
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
-	defer stop()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	<-ctx.Done()
+	if got, want := string(body), "hello, world!\n"; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
+
+func TestServeContentRangeContentLengthWithEncoding(t *testing.T) {
+	h := &fileHandler{
+		errHandler: func(w http.ResponseWriter, r *http.Request, code int, err error) {
+			http.Error(w, http.StatusText(code), code)
+		},
+	}
+
+	const body = "0123456789"
+
+	r := httptest.NewRequest(http.MethodGet, "/file.txt", nil)
+	r.Header.Set("Range", "bytes=2-4")
+
+	w := httptest.NewRecorder()
+	w.Header().Set("Content-Encoding", "gzip")
+	w.Header().Set("Content-Length", "999")
+
+	h.serveContent(w, r, "file.txt", time.Time{}, func() (int64, error) { return int64(len(body)), nil }, strings.NewReader(body))
+
+	res := w.Result()
+	if res.StatusCode != http.StatusPartialContent {
+		t.Fatalf("want status %d, got %d", http.StatusPartialContent, res.StatusCode)
+	}
+
+	if got, want := res.Header.Get("Content-Length"), "3"; got != want {
+		t.Errorf("Content-Length = %q, want %q", got, want)
+	}
+
+	if got, want := w.Body.String(), "234"; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
+
+type fakeFileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+func (fi fakeFileInfo) Name() string       { return fi.name }
+func (fi fakeFileInfo) Size() int64        { return fi.size }
+func (fi fakeFileInfo) Mode() fs.FileMode  { return 0 }
+func (fi fakeFileInfo) ModTime() time.Time { return fi.modTime }
+func (fi fakeFileInfo) IsDir() bool        { return false }
+func (fi fakeFileInfo) Sys() any           { return nil }
+
+func TestDefaultETagFunc(t *testing.T) {
+	t.Run("small file hashes contents", func(t *testing.T) {
+		dir := t.TempDir()
+		name := filepath.Join(dir, "a.txt")
+		if err := os.WriteFile(name, []byte("hello world"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		f, err := os.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		fi, err := f.Stat()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sum := sha256.Sum256([]byte("hello world"))
+		want := hex.EncodeToString(sum[:])
+
+		etag, weak, err := DefaultETagFunc(fi.Name(), fi, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if weak {
+			t.Error("want strong etag for small file, got weak")
+		}
+
+		if etag != want {
+			t.Errorf("etag = %q, want %q", etag, want)
+		}
+
+		pos, err := f.Seek(0, io.SeekCurrent)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if pos != 0 {
+			t.Errorf("file offset = %d after hashing, want 0 (not rewound)", pos)
+		}
+	})
+
+	t.Run("large file gets a cheap weak etag", func(t *testing.T) {
+		modTime := time.Unix(1700000000, 0)
+		fi := fakeFileInfo{name: "big.bin", size: defaultETagMaxSize + 1, modTime: modTime}
+
+		etag, weak, err := DefaultETagFunc(fi.name, fi, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !weak {
+			t.Error("want weak etag for large file, got strong")
+		}
+
+		want := fmt.Sprintf("%x-%x", fi.Size(), fi.ModTime().UnixNano())
+		if etag != want {
+			t.Errorf("etag = %q, want %q", etag, want)
+		}
+	})
+}
+
+func TestFileServerDefaultETagFunc(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("hello world"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	h := FileServer(
+		http.Dir(dir),
+		func(w http.ResponseWriter, r *http.Request, code int, err error) {
+			http.Error(w, http.StatusText(code), code)
+		},
+		nil,
+		WithETagFunc(DefaultETagFunc),
+	)
+
+	r := httptest.NewRequest(http.MethodGet, "/a.txt", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	etag := w.Result().Header.Get("Etag")
+	if etag == "" {
+		t.Fatal("want Etag header to be set, got none")
+	}
+
+	r2 := httptest.NewRequest(http.MethodGet, "/a.txt", nil)
+	r2.Header.Set("If-None-Match", etag)
+
+	w2 := httptest.NewRecorder()
+	h.ServeHTTP(w2, r2)
+
+	if got, want := w2.Result().StatusCode, http.StatusNotModified; got != want {
+		t.Errorf("status = %d, want %d", got, want)
+	}
+}
+
+func TestFileServerPrecompressed(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "app.js"), []byte("console.log('original')"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	const gzipBody = "stand-in-gzip-bytes-0123456789"
+	if err := os.WriteFile(filepath.Join(dir, "app.js.gz"), []byte(gzipBody), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	h := FileServer(
+		http.Dir(dir),
+		func(w http.ResponseWriter, r *http.Request, code int, err error) {
+			http.Error(w, http.StatusText(code), code)
+		},
+		nil,
+		WithPrecompressedEncodings("gzip", "br"),
+	)
+
+	r := httptest.NewRequest(http.MethodGet, "/app.js", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+	r.Header.Set("Range", "bytes=0-4")
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	res := w.Result()
+	if got, want := res.StatusCode, http.StatusPartialContent; got != want {
+		t.Fatalf("status = %d, want %d", got, want)
+	}
+
+	if got, want := res.Header.Get("Content-Encoding"), "gzip"; got != want {
+		t.Errorf("Content-Encoding = %q, want %q", got, want)
+	}
+
+	if got, want := res.Header.Get("Vary"), "Accept-Encoding"; got != want {
+		t.Errorf("Vary = %q, want %q", got, want)
+	}
+
+	if got, want := res.Header.Get("Content-Length"), "5"; got != want {
+		t.Errorf("Content-Length = %q, want %q", got, want)
+	}
+
+	if got, want := w.Body.String(), gzipBody[:5]; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+
+	r2 := httptest.NewRequest(http.MethodGet, "/app.js", nil)
+	w2 := httptest.NewRecorder()
+	h.ServeHTTP(w2, r2)
+
+	if got := w2.Result().Header.Get("Content-Encoding"); got != "" {
+		t.Errorf("Content-Encoding = %q, want none without Accept-Encoding", got)
+	}
+
+	if got, want := w2.Body.String(), "console.log('original')"; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
+
+func TestFileServerPrecompressedPreference(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "app.js"), []byte("console.log('original')"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "app.js.gz"), []byte("gzip-body"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "app.js.br"), []byte("br-body"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	h := FileServer(
+		http.Dir(dir),
+		func(w http.ResponseWriter, r *http.Request, code int, err error) {
+			http.Error(w, http.StatusText(code), code)
+		},
+		nil,
+		WithPrecompressedEncodings("gzip", "br"),
+	)
+
+	r := httptest.NewRequest(http.MethodGet, "/app.js", nil)
+	r.Header.Set("Accept-Encoding", "gzip, br")
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if got, want := w.Result().Header.Get("Content-Encoding"), "br"; got != want {
+		t.Errorf("Content-Encoding = %q, want %q (br preferred over gzip at equal quality)", got, want)
+	}
+
+	if got, want := w.Body.String(), "br-body"; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
+
+func TestFileServerPrecompressedConditionalUsesSidecarMetadata(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "app.js"), []byte("console.log('original')"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "app.js.gz"), []byte("gzip-body"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	h := FileServer(
+		http.Dir(dir),
+		func(w http.ResponseWriter, r *http.Request, code int, err error) {
+			http.Error(w, http.StatusText(code), code)
+		},
+		nil,
+		WithPrecompressedEncodings("gzip"),
+		WithETagFunc(DefaultETagFunc),
+	)
+
+	r := httptest.NewRequest(http.MethodGet, "/app.js", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	sidecarETag := w.Result().Header.Get("Etag")
+	if sidecarETag == "" {
+		t.Fatal("want Etag header to be set from sidecar contents")
+	}
+
+	sum := sha256.Sum256([]byte("gzip-body"))
+	if want := `"` + hex.EncodeToString(sum[:]) + `"`; sidecarETag != want {
+		t.Errorf("Etag = %q, want %q (hashed from sidecar, not original)", sidecarETag, want)
+	}
+
+	r2 := httptest.NewRequest(http.MethodGet, "/app.js", nil)
+	r2.Header.Set("Accept-Encoding", "gzip")
+	r2.Header.Set("If-None-Match", sidecarETag)
+
+	w2 := httptest.NewRecorder()
+	h.ServeHTTP(w2, r2)
 
-	srv.Close()
+	if got, want := w2.Result().StatusCode, http.StatusNotModified; got != want {
+		t.Errorf("status = %d, want %d for If-None-Match against sidecar etag", got, want)
+	}
 }
@@ -0,0 +1,136 @@
+// Copyright 2009 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fileserver
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// FileServerFS returns a handler that serves HTTP requests with the contents
+// of the file system fsys. It is the [io/fs.FS] equivalent of [FileServer],
+// useful for serving content embedded with [embed.FS] or other fs.FS
+// implementations without hand-rolling an [http.FileSystem] adapter.
+func FileServerFS(
+	fsys fs.FS,
+	errHandler func(http.ResponseWriter, *http.Request, int, error),
+	dirListHandler func(http.ResponseWriter, *http.Request, []FileEntry),
+) *fileHandler {
+	return FileServer(ioFS{fsys}, errHandler, dirListHandler)
+}
+
+// ServeFileFS serves the named file from fsys, using errHandler to report
+// errors and dirListHandler to render name if it turns out to be a
+// directory. It is the [io/fs.FS] equivalent of calling [FileServer] and
+// serving a single path out of the resulting handler.
+func ServeFileFS(
+	w http.ResponseWriter,
+	r *http.Request,
+	fsys fs.FS,
+	name string,
+	errHandler func(http.ResponseWriter, *http.Request, int, error),
+	dirListHandler func(http.ResponseWriter, *http.Request, []FileEntry),
+) {
+	if containsDotDot(r.URL.Path) {
+		errHandler(w, r, http.StatusBadRequest, errors.New("invalid URL path"))
+		return
+	}
+
+	h := FileServer(ioFS{fsys}, errHandler, dirListHandler)
+	h.serveFile(w, r, h.root, path.Clean(name), false)
+}
+
+func containsDotDot(v string) bool {
+	if !strings.Contains(v, "..") {
+		return false
+	}
+
+	for _, ent := range strings.FieldsFunc(v, func(r rune) bool { return r == '/' }) {
+		if ent == ".." {
+			return true
+		}
+	}
+
+	return false
+}
+
+var (
+	errMissingSeek    = errors.New("io.File missing Seek method")
+	errMissingReadDir = errors.New("io.File directory missing ReadDir method")
+)
+
+// ioFS adapts an [io/fs.FS] to the [http.FileSystem] interface so the
+// rest of fileHandler can remain unaware of which one it was given.
+type ioFS struct {
+	fsys fs.FS
+}
+
+func (f ioFS) Open(name string) (http.File, error) {
+	if name == "/" {
+		name = "."
+	} else {
+		name = strings.TrimPrefix(name, "/")
+	}
+
+	file, err := f.fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return ioFile{file}, nil
+}
+
+type ioFile struct {
+	file fs.File
+}
+
+func (f ioFile) Close() error { return f.file.Close() }
+
+func (f ioFile) Read(b []byte) (int, error) { return f.file.Read(b) }
+
+func (f ioFile) Seek(offset int64, whence int) (int64, error) {
+	s, ok := f.file.(io.Seeker)
+	if !ok {
+		return 0, errMissingSeek
+	}
+
+	return s.Seek(offset, whence)
+}
+
+func (f ioFile) ReadDir(count int) ([]fs.DirEntry, error) {
+	d, ok := f.file.(fs.ReadDirFile)
+	if !ok {
+		return nil, errMissingReadDir
+	}
+
+	return d.ReadDir(count)
+}
+
+func (f ioFile) Readdir(count int) ([]fs.FileInfo, error) {
+	d, ok := f.file.(fs.ReadDirFile)
+	if !ok {
+		return nil, errMissingReadDir
+	}
+
+	dirs, err := d.ReadDir(count)
+
+	infos := make([]fs.FileInfo, 0, len(dirs))
+	for _, dir := range dirs {
+		info, infoErr := dir.Info()
+		if infoErr != nil {
+			continue
+		}
+
+		infos = append(infos, info)
+	}
+
+	return infos, err
+}
+
+func (f ioFile) Stat() (fs.FileInfo, error) { return f.file.Stat() }
@@ -0,0 +1,90 @@
+package fileserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func testErrHandler(t *testing.T) func(http.ResponseWriter, *http.Request, int, error) {
+	return func(w http.ResponseWriter, r *http.Request, code int, err error) {
+		t.Logf("errHandler: %d: %v", code, err)
+		http.Error(w, http.StatusText(code), code)
+	}
+}
+
+func TestFileServerFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"static/app.js":   &fstest.MapFile{Data: []byte("console.log('hi')")},
+		"static/note.txt": &fstest.MapFile{Data: []byte("a note")},
+	}
+
+	var listed []FileEntry
+	h := FileServerFS(
+		fsys,
+		testErrHandler(t),
+		func(w http.ResponseWriter, r *http.Request, fe []FileEntry) {
+			listed = fe
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		},
+	)
+
+	r := httptest.NewRequest(http.MethodGet, "/static/app.js", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if got, want := w.Result().StatusCode, http.StatusOK; got != want {
+		t.Fatalf("status = %d, want %d", got, want)
+	}
+
+	if got, want := w.Body.String(), "console.log('hi')"; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+
+	r2 := httptest.NewRequest(http.MethodGet, "/static/", nil)
+	w2 := httptest.NewRecorder()
+	h.ServeHTTP(w2, r2)
+
+	if got, want := w2.Result().StatusCode, http.StatusOK; got != want {
+		t.Fatalf("status = %d, want %d", got, want)
+	}
+
+	if len(listed) != 2 {
+		t.Fatalf("got %d directory entries, want 2: %+v", len(listed), listed)
+	}
+}
+
+func TestServeFileFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"hello.txt": &fstest.MapFile{Data: []byte("hello, fs!")},
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/hello.txt", nil)
+	w := httptest.NewRecorder()
+
+	ServeFileFS(w, r, fsys, "hello.txt", testErrHandler(t), nil)
+
+	if got, want := w.Result().StatusCode, http.StatusOK; got != want {
+		t.Fatalf("status = %d, want %d", got, want)
+	}
+
+	if got, want := w.Body.String(), "hello, fs!"; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
+
+func TestServeFileFSRejectsDotDot(t *testing.T) {
+	fsys := fstest.MapFS{
+		"hello.txt": &fstest.MapFile{Data: []byte("hello, fs!")},
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/../hello.txt", nil)
+	w := httptest.NewRecorder()
+
+	ServeFileFS(w, r, fsys, "hello.txt", testErrHandler(t), nil)
+
+	if got, want := w.Result().StatusCode, http.StatusBadRequest; got != want {
+		t.Errorf("status = %d, want %d", got, want)
+	}
+}
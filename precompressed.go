@@ -0,0 +1,124 @@
+package fileserver
+
+import (
+	"io/fs"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// precompressedExt maps a content coding to the sidecar file extension
+// that holds it.
+var precompressedExt = map[string]string{
+	"br":   ".br",
+	"zstd": ".zst",
+	"gzip": ".gz",
+}
+
+// precompressedPreference breaks ties between codings of equal quality,
+// most preferred first.
+var precompressedPreference = []string{"br", "zstd", "gzip"}
+
+// openPrecompressed probes fsys for a pre-compressed sidecar of name
+// (name+".br", name+".zst", or name+".gz") that satisfies the request's
+// Accept-Encoding header and h.precompressedEncodings, returning the
+// first match in preference order.
+func (h *fileHandler) openPrecompressed(fsys http.FileSystem, name string, r *http.Request) (fs.FileInfo, http.File, string, bool) {
+	for _, coding := range acceptedEncodings(r.Header.Get("Accept-Encoding"), h.precompressedEncodings) {
+		ext, ok := precompressedExt[coding]
+		if !ok {
+			continue
+		}
+
+		sf, err := fsys.Open(name + ext)
+		if err != nil {
+			continue
+		}
+
+		sd, err := sf.Stat()
+		if err != nil || sd.IsDir() {
+			sf.Close()
+			continue
+		}
+
+		return sd, sf, coding, true
+	}
+
+	return nil, nil, "", false
+}
+
+// acceptedEncodings parses an Accept-Encoding header and returns the
+// codings in allowed that the client accepts, ordered by descending
+// quality and, for ties, by precompressedPreference.
+func acceptedEncodings(acceptEncoding string, allowed []string) []string {
+	if acceptEncoding == "" || len(allowed) == 0 {
+		return nil
+	}
+
+	allowedSet := make(map[string]bool, len(allowed))
+	for _, a := range allowed {
+		allowedSet[strings.ToLower(strings.TrimSpace(a))] = true
+	}
+
+	type candidate struct {
+		coding string
+		q      float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "" || !allowedSet[coding] {
+			continue
+		}
+
+		if _, ok := precompressedExt[coding]; !ok {
+			continue
+		}
+
+		q := 1.0
+		for _, p := range strings.Split(params, ";") {
+			k, v, ok := strings.Cut(strings.TrimSpace(p), "=")
+			if !ok || strings.TrimSpace(k) != "q" {
+				continue
+			}
+
+			if parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				q = parsed
+			}
+		}
+
+		if q <= 0 {
+			continue
+		}
+
+		candidates = append(candidates, candidate{coding, q})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].q != candidates[j].q {
+			return candidates[i].q > candidates[j].q
+		}
+
+		return precompressedRank(candidates[i].coding) < precompressedRank(candidates[j].coding)
+	})
+
+	out := make([]string, len(candidates))
+	for i, c := range candidates {
+		out[i] = c.coding
+	}
+
+	return out
+}
+
+func precompressedRank(coding string) int {
+	for i, c := range precompressedPreference {
+		if c == coding {
+			return i
+		}
+	}
+
+	return len(precompressedPreference)
+}